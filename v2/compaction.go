@@ -0,0 +1,94 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+// len(o.backing) is the number of slots that have ever been filled: live
+// elements plus tombstones left behind by Remove.  Len() only counts live
+// elements, so cap(o.backing) (and len(o.backing)) can grow arbitrarily
+// larger than Len() under add/remove churn, in particular in the
+// pathological case where every element shares a hash code: each Remove
+// only drops the hashToIndex entry once its index list becomes empty, but
+// the tombstoned backing slot itself is never reused.  compactionThreshold
+// and maybeCompact keep that growth bounded by triggering a rebuild once
+// more than compactionThreshold slots are filled and tombstones make up
+// more than half of them.
+const compactionThreshold = 16
+
+func shouldCompact(filled int, live int) bool {
+	return filled > compactionThreshold && filled > 2*live
+}
+
+// Compact immediately reclaims tombstone slots left behind by Remove,
+// rebuilding backing and hashToIndex from scratch.  It is normally not
+// necessary to call this directly, since Add calls it automatically once
+// tombstones accumulate, but it is useful to force reclamation right after
+// a bulk Remove.
+func (o *OrderedSet[T]) Compact() {
+	o.rebuild()
+}
+
+func (o *OrderedSet[T]) maybeCompact() {
+	if shouldCompact(len(o.backing), o.len) {
+		o.rebuild()
+	}
+}
+
+func (o *OrderedSet[T]) rebuild() {
+	newBacking := make([]T, 0, o.len)
+	newOccupied := make([]bool, 0, o.len)
+	newHashToIndex := make(map[int][]int, len(o.hashToIndex))
+	for i, occupied := range o.occupied {
+		if !occupied {
+			continue
+		}
+		element := o.backing[i]
+		hash := o.equality.Hash(element)
+		newIndex := len(newBacking)
+		newBacking = append(newBacking, element)
+		newOccupied = append(newOccupied, true)
+		newHashToIndex[hash] = append(newHashToIndex[hash], newIndex)
+	}
+	o.backing = newBacking
+	o.occupied = newOccupied
+	o.hashToIndex = newHashToIndex
+}
+
+// Compact immediately reclaims tombstone slots left behind by Remove,
+// rebuilding backing, valueBacking and hashToIndex from scratch.  It is
+// normally not necessary to call this directly, since Set calls it
+// automatically once tombstones accumulate, but it is useful to force
+// reclamation right after a bulk Remove.
+func (o *OrderedMap[K, V]) Compact() {
+	o.rebuild()
+}
+
+func (o *OrderedMap[K, V]) maybeCompact() {
+	if shouldCompact(len(o.backing), o.len) {
+		o.rebuild()
+	}
+}
+
+func (o *OrderedMap[K, V]) rebuild() {
+	newBacking := make([]K, 0, o.len)
+	newValueBacking := make([]V, 0, o.len)
+	newOccupied := make([]bool, 0, o.len)
+	newHashToIndex := make(map[int][]int, len(o.hashToIndex))
+	for i, occupied := range o.occupied {
+		if !occupied {
+			continue
+		}
+		key := o.backing[i]
+		hash := o.equality.Hash(key)
+		newIndex := len(newBacking)
+		newBacking = append(newBacking, key)
+		newValueBacking = append(newValueBacking, o.valueBacking[i])
+		newOccupied = append(newOccupied, true)
+		newHashToIndex[hash] = append(newHashToIndex[hash], newIndex)
+	}
+	o.backing = newBacking
+	o.valueBacking = newValueBacking
+	o.occupied = newOccupied
+	o.hashToIndex = newHashToIndex
+}
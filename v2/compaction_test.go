@@ -0,0 +1,60 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import "testing"
+
+func TestCompaction(t *testing.T) {
+	s := newStressSet()
+	for i := 0; i < 1000; i++ {
+		s.Add(string(rune('a' + i%26)))
+		s.Remove(string(rune('a' + i%26)))
+	}
+	if s.Len() != 0 {
+		t.Fatal("Len")
+	}
+	if len(s.backing) > 2*compactionThreshold {
+		t.Fatalf("tombstones were not reclaimed: len(backing) = %d", len(s.backing))
+	}
+
+	s.Add("Foo")
+	s.Add("Bar")
+	before := len(s.backing)
+	s.Remove("Foo")
+	s.Compact()
+	if len(s.backing) >= before {
+		t.Fatal("Compact did not shrink backing")
+	}
+	if s.Len() != 1 || !s.Contains("Bar") {
+		t.Fatal("Compact lost live data")
+	}
+}
+
+func TestMapCompaction(t *testing.T) {
+	m := newStressMap()
+	for i := 0; i < 1000; i++ {
+		key := string(rune('a' + i%26))
+		m.Set(key, key)
+		m.Remove(key)
+	}
+	if m.Len() != 0 {
+		t.Fatal("Len")
+	}
+	if len(m.backing) > 2*compactionThreshold {
+		t.Fatalf("tombstones were not reclaimed: len(backing) = %d", len(m.backing))
+	}
+}
+
+// BenchmarkStressSetChurn exercises the pathological all-same-hash case:
+// without compaction, repeated add/remove churn would make backing grow
+// without bound and every lookup O(n).
+func BenchmarkStressSetChurn(b *testing.B) {
+	s := newStressSet()
+	for i := 0; i < b.N; i++ {
+		key := string(rune('a' + i%26))
+		s.Add(key)
+		s.Remove(key)
+	}
+}
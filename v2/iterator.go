@@ -0,0 +1,207 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import "iter"
+
+// Entries() and Values() allocate and fully materialize a slice even when a
+// caller only wants to look at the first few elements, or wants to stop as
+// soon as it finds what it is looking for.  Iterator, Each and EachKV give
+// callers a way to walk a set or map without that allocation and with the
+// option to stop early.
+//
+// As with the rest of this package, modifying a set or map while an
+// Iterator over it is in use, or while Each/EachKV is running, is
+// undefined: the iteration may or may not see the change.
+
+// cursor is the direction-agnostic bookkeeping shared by Iterator and
+// MapIterator.  backingIndex is the raw slot in the backing slice, or -1
+// before iteration has started in either direction.  logicalIndex is the
+// 0-based position among the live (non-tombstone) elements, matching the
+// index a caller would see in Entries()/Values().
+type cursor struct {
+	backingIndex int
+	logicalIndex int
+}
+
+func newCursor() cursor {
+	return cursor{backingIndex: -1, logicalIndex: -1}
+}
+
+func (c *cursor) reset() {
+	c.backingIndex = -1
+	c.logicalIndex = -1
+}
+
+func (c *cursor) next(occupied []bool) bool {
+	for i := c.backingIndex + 1; i < len(occupied); i++ {
+		if occupied[i] {
+			c.backingIndex = i
+			c.logicalIndex++
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cursor) prev(occupied []bool, liveCount int) bool {
+	start := c.backingIndex - 1
+	if c.backingIndex == -1 {
+		start = len(occupied) - 1
+	}
+	for i := start; i >= 0; i-- {
+		if occupied[i] {
+			c.backingIndex = i
+			if c.logicalIndex == -1 {
+				c.logicalIndex = liveCount - 1
+			} else {
+				c.logicalIndex--
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Iterator walks the elements of an OrderedSet in insertion order.  An
+// OrderedMap is walked with a MapIterator instead.
+type Iterator[T any] struct {
+	cursor
+	hash *orderedHash[T]
+}
+
+// Iterator returns a fresh Iterator positioned before the first element.
+func (o *OrderedSet[T]) Iterator() *Iterator[T] {
+	return &Iterator[T]{cursor: newCursor(), hash: &o.orderedHash}
+}
+
+// Next advances the iterator to the next live element, skipping tombstones,
+// and reports whether there was one.
+func (it *Iterator[T]) Next() bool {
+	return it.cursor.next(it.hash.occupied)
+}
+
+// Prev moves the iterator to the previous live element, skipping
+// tombstones, and reports whether there was one.
+func (it *Iterator[T]) Prev() bool {
+	return it.cursor.prev(it.hash.occupied, it.hash.len)
+}
+
+// Reset repositions the iterator before the first element, as if freshly
+// created.
+func (it *Iterator[T]) Reset() {
+	it.cursor.reset()
+}
+
+// Value returns the element at the iterator's current position.
+func (it *Iterator[T]) Value() T {
+	return it.hash.backing[it.backingIndex]
+}
+
+// Index returns the 0-based position of the current element among the
+// live elements, matching the index it would have in Entries().
+func (it *Iterator[T]) Index() int {
+	return it.logicalIndex
+}
+
+// MapIterator walks the key-value pairs of an OrderedMap in insertion
+// order.
+type MapIterator[K any, V any] struct {
+	cursor
+	m *OrderedMap[K, V]
+}
+
+// Iterator returns a fresh MapIterator positioned before the first pair.
+func (o *OrderedMap[K, V]) Iterator() *MapIterator[K, V] {
+	return &MapIterator[K, V]{cursor: newCursor(), m: o}
+}
+
+// Next advances the iterator to the next live pair, skipping tombstones,
+// and reports whether there was one.
+func (it *MapIterator[K, V]) Next() bool {
+	return it.cursor.next(it.m.occupied)
+}
+
+// Prev moves the iterator to the previous live pair, skipping tombstones,
+// and reports whether there was one.
+func (it *MapIterator[K, V]) Prev() bool {
+	return it.cursor.prev(it.m.occupied, it.m.len)
+}
+
+// Reset repositions the iterator before the first pair, as if freshly
+// created.
+func (it *MapIterator[K, V]) Reset() {
+	it.cursor.reset()
+}
+
+// Key returns the key at the iterator's current position.
+func (it *MapIterator[K, V]) Key() K {
+	return it.m.backing[it.backingIndex]
+}
+
+// Value returns the value at the iterator's current position.
+func (it *MapIterator[K, V]) Value() V {
+	return it.m.valueBacking[it.backingIndex]
+}
+
+// Index returns the 0-based position of the current pair among the live
+// pairs, matching the index it would have in Entries()/Values().
+func (it *MapIterator[K, V]) Index() int {
+	return it.logicalIndex
+}
+
+// Each calls f once for every element of the set, in insertion order, with
+// the element's logical index and value.  If f returns false, Each stops
+// immediately without visiting the remaining elements.
+func (o *OrderedSet[T]) Each(f func(i int, v T) bool) {
+	i := 0
+	for index, occupied := range o.occupied {
+		if !occupied {
+			continue
+		}
+		if !f(i, o.backing[index]) {
+			return
+		}
+		i++
+	}
+}
+
+// EachKV calls f once for every key-value pair of the map, in insertion
+// order, with the pair's logical index, key and value.  If f returns
+// false, EachKV stops immediately without visiting the remaining pairs.
+func (o *OrderedMap[K, V]) EachKV(f func(i int, k K, v V) bool) {
+	i := 0
+	for index, occupied := range o.occupied {
+		if !occupied {
+			continue
+		}
+		if !f(i, o.backing[index], o.valueBacking[index]) {
+			return
+		}
+		i++
+	}
+}
+
+// All returns a Go 1.23 range-over-func iterator that yields each element's
+// logical index and value, in insertion order.  It allows the idiom
+//
+//	for i, v := range s.All() { ... }
+func (o *OrderedSet[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		o.Each(yield)
+	}
+}
+
+// All returns a Go 1.23 range-over-func iterator that yields each key and
+// its value, in insertion order.  It allows the idiom
+//
+//	for k, v := range m.All() { ... }
+func (o *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		o.EachKV(func(_ int, k K, v V) bool {
+			return yield(k, v)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetIterator(t *testing.T) {
+	s := stringSetOf("Foo", "Bar", "Fizz")
+	s.Remove("Bar")
+	s.Add("Buzz")
+
+	it := s.Iterator()
+	var forward []string
+	for it.Next() {
+		forward = append(forward, it.Value())
+	}
+	if !reflect.DeepEqual(forward, []string{"Foo", "Fizz", "Buzz"}) {
+		t.Fatal(forward)
+	}
+
+	var backward []string
+	for it.Prev() {
+		backward = append(backward, it.Value())
+	}
+	if !reflect.DeepEqual(backward, []string{"Fizz", "Foo"}) {
+		t.Fatal(backward)
+	}
+
+	it.Reset()
+	if !it.Next() || it.Value() != "Foo" || it.Index() != 0 {
+		t.Fatal("Reset did not return to the start")
+	}
+}
+
+func TestSetEachAndAll(t *testing.T) {
+	s := stringSetOf("Foo", "Bar", "Fizz")
+
+	var collected []string
+	s.Each(func(i int, v string) bool {
+		collected = append(collected, v)
+		return v != "Bar"
+	})
+	if !reflect.DeepEqual(collected, []string{"Foo", "Bar"}) {
+		t.Fatal(collected)
+	}
+
+	var all []string
+	for _, v := range s.All() {
+		all = append(all, v)
+	}
+	if !reflect.DeepEqual(all, []string{"Foo", "Bar", "Fizz"}) {
+		t.Fatal(all)
+	}
+}
+
+func TestMapIteratorAndEachKV(t *testing.T) {
+	m := NewStringMap[int]()
+	m.Set("Foo", 1)
+	m.Set("Bar", 2)
+	m.Set("Fizz", 3)
+
+	it := m.Iterator()
+	var keys []string
+	var values []int
+	for it.Next() {
+		keys = append(keys, it.Key())
+		values = append(values, it.Value())
+	}
+	if !reflect.DeepEqual(keys, []string{"Foo", "Bar", "Fizz"}) {
+		t.Fatal(keys)
+	}
+	if !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Fatal(values)
+	}
+
+	var kvs []string
+	m.EachKV(func(i int, k string, v int) bool {
+		kvs = append(kvs, k)
+		return k != "Bar"
+	})
+	if !reflect.DeepEqual(kvs, []string{"Foo", "Bar"}) {
+		t.Fatal(kvs)
+	}
+
+	collected := map[string]int{}
+	for k, v := range m.All() {
+		collected[k] = v
+	}
+	if !reflect.DeepEqual(collected, map[string]int{"Foo": 1, "Bar": 2, "Fizz": 3}) {
+		t.Fatal(collected)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+// Insertion-ordered hash set and hash map with an implementation inspired by
+// the non-hateful maps described in
+// https://blog.toit.io/hash-maps-that-dont-hate-you-1a96150b492a
+// The collections preserve insertion order and allow custom equality
+// functions.  OrderedSet and OrderedMap themselves are not thread safe and
+// require external synchronization; use SyncOrderedSet/SyncOrderedMap if
+// concurrent access is required.
+//
+// This is the /v2 module: it replaces the interface{}-based API of the v1
+// orderedhash package with Go generics.  The implementation shape (backing
+// slice, hashToIndex map, tombstones) is unchanged; only the element and key
+// types are now statically checked instead of relying on interface{} type
+// assertions.
+package orderedhash
+
+// To use OrderedMap or OrderedSet you must first define an EqualityRelation
+// for your set elements or map keys.  The two methods in this interface must
+// be coherent in that if two objects are equal, they must have the same hash
+// code.  Hash collisions are allowed but will reduce efficiency if they are
+// frequent.  The hash code returned should not change on subsequent calls,
+// and the return value from Equals should also be stable.
+type EqualityRelation[T any] interface {
+	Equals(objectInCollection T, newObject T) bool
+	Hash(object T) int
+}
+
+// A generic EqualityRelation for any comparable type, using == for equality
+// and a caller-supplied hash function.  This is the generic replacement for
+// writing a bespoke EqualityRelation whenever the element type already
+// supports ==.
+type ComparableEquality[T comparable] struct {
+	HashFunc func(T) int
+}
+
+func (e ComparableEquality[T]) Equals(a T, b T) bool {
+	return a == b
+}
+
+func (e ComparableEquality[T]) Hash(a T) int {
+	return e.HashFunc(a)
+}
+
+// orderedHash is the shared implementation behind OrderedSet and OrderedMap.
+// Since generic methods cannot be parameterized beyond the type parameters
+// of their receiver, orderedHash is generic only in the element/key type T;
+// OrderedMap adds its value slice alongside an embedded orderedHash[K].
+//
+// Unlike the v1 implementation, which used a nil entry in backing as the
+// tombstone marker, T has no universal nil value, so a removed slot is
+// instead marked in the parallel occupied slice.
+type orderedHash[T any] struct {
+	hashToIndex map[int][]int
+	backing     []T
+	occupied    []bool
+	equality    EqualityRelation[T]
+	len         int
+}
+
+// An insertion-ordered hash set with customizable equality function.
+type OrderedSet[T any] struct {
+	orderedHash[T]
+}
+
+// An insertion-ordered hash map with customizable equality function.
+type OrderedMap[K any, V any] struct {
+	orderedHash[K]
+	valueBacking []V
+}
+
+// Create an empty insertion-ordered set with customized equality function.
+func NewSet[T any](relation EqualityRelation[T]) *OrderedSet[T] {
+	new := OrderedSet[T]{
+		orderedHash[T]{
+			hashToIndex: make(map[int][]int),
+			backing:     []T{},
+			occupied:    []bool{},
+			equality:    relation,
+		},
+	}
+	return &new
+}
+
+// Create an empty insertion-ordered map with customized equality function.
+func NewMap[K any, V any](relation EqualityRelation[K]) *OrderedMap[K, V] {
+	new := OrderedMap[K, V]{
+		orderedHash: orderedHash[K]{
+			hashToIndex: make(map[int][]int),
+			backing:     []K{},
+			occupied:    []bool{},
+			equality:    relation,
+		},
+		valueBacking: []V{},
+	}
+	return &new
+}
+
+// Get the number of elements in the set or the number of key-value pairs in
+// the map.  This counts only live elements: cap(o.backing) (and even
+// len(o.backing)) can be larger, since tombstones left behind by Remove are
+// not reclaimed until a compaction runs; see Compact.
+func (o *orderedHash[T]) Len() int {
+	return o.len
+}
+
+// Add the element to the set if it does not already contain an equal element.
+func (o *OrderedSet[T]) Add(element T) {
+	hash := o.equality.Hash(element)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		// No entries with this hash code.  Create a new one.
+		index := len(o.backing)
+		o.backing = append(o.backing, element)
+		o.occupied = append(o.occupied, true)
+		o.hashToIndex[hash] = []int{index}
+		o.len++
+		o.maybeCompact()
+		return
+	}
+	deleted_space := -1
+	for i, index := range indices {
+		if o.occupied[index] {
+			if o.equality.Equals(o.backing[index], element) {
+				// Already present in set.
+				return
+			}
+		} else if deleted_space == -1 {
+			deleted_space = i
+		}
+	}
+	// Not found.  Add an index to the entry for this hash code.
+	index := len(o.backing)
+	o.backing = append(o.backing, element)
+	o.occupied = append(o.occupied, true)
+	if deleted_space == -1 {
+		o.hashToIndex[hash] = append(indices, index)
+	} else {
+		indices[deleted_space] = index
+	}
+	o.len++
+	o.maybeCompact()
+}
+
+// Add the key and value to the map.  If the map already contains an
+// equal key then the value is overwritten, but the key is unchanged.
+func (o *OrderedMap[K, V]) Set(key K, value V) {
+	hash := o.equality.Hash(key)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		// No entries with this hash code.  Create a new one.
+		index := len(o.backing)
+		o.backing = append(o.backing, key)
+		o.occupied = append(o.occupied, true)
+		o.valueBacking = append(o.valueBacking, value)
+		o.hashToIndex[hash] = []int{index}
+		o.len++
+		o.maybeCompact()
+		return
+	}
+	deleted_space := -1
+	for i, index := range indices {
+		if o.occupied[index] {
+			if o.equality.Equals(o.backing[index], key) {
+				// Already present in map.  Overwrite value.
+				o.valueBacking[index] = value
+				return
+			}
+		} else if deleted_space == -1 {
+			deleted_space = i
+		}
+	}
+	// Not found.  Add an index to the entry for this hash code.
+	index := len(o.backing)
+	o.backing = append(o.backing, key)
+	o.occupied = append(o.occupied, true)
+	o.valueBacking = append(o.valueBacking, value)
+	if deleted_space == -1 {
+		o.hashToIndex[hash] = append(indices, index)
+	} else {
+		indices[deleted_space] = index
+	}
+	o.len++
+	o.maybeCompact()
+}
+
+// Whether the set contains an equal element, or whether the map
+// contains an equal key.
+func (o *orderedHash[T]) Contains(element T) bool {
+	hash := o.equality.Hash(element)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		return false
+	}
+	for _, index := range indices {
+		if o.occupied[index] && o.equality.Equals(o.backing[index], element) {
+			// Already present in set.
+			return true
+		}
+	}
+	return false
+}
+
+// Get an equal element that is already in the set or an equal key that is
+// already in the map.  The second return value reports whether an equal
+// element or key was found.
+func (o *orderedHash[T]) GetKey(element T) (T, bool) {
+	hash := o.equality.Hash(element)
+	indices := o.hashToIndex[hash]
+	if indices != nil {
+		for _, index := range indices {
+			if o.occupied[index] && o.equality.Equals(o.backing[index], element) {
+				// Found.
+				return o.backing[index], true
+			}
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Get the value corresponding to a key.  The second return value reports
+// whether an equal key was found in the map.
+func (o *OrderedMap[K, V]) Get(key K) (V, bool) {
+	hash := o.equality.Hash(key)
+	indices := o.hashToIndex[hash]
+	if indices != nil {
+		for _, index := range indices {
+			if o.occupied[index] && o.equality.Equals(o.backing[index], key) {
+				// Found.
+				return o.valueBacking[index], true
+			}
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Remove an equal element from a set.
+// If an element is removed and then later re-added, its iteration order
+// is moved to the end.
+func (o *OrderedSet[T]) Remove(element T) {
+	hash := o.equality.Hash(element)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		// No entries with this hash code.
+		return
+	}
+	for _, index := range indices {
+		if o.occupied[index] && o.equality.Equals(o.backing[index], element) {
+			// Found.  We mark the slot as a tombstone.
+			o.occupied[index] = false
+			o.len--
+			// If there was only one entry in the map with this hash code
+			// we might as well remove it.  TODO: We could also remove a
+			// single entry when there are hash collisions.
+			if len(indices) == 1 {
+				delete(o.hashToIndex, hash)
+			}
+		}
+	}
+}
+
+// Remove an equal key and its associated value from a map.
+// If a key is removed and then later re-added, its iteration order
+// is moved to the end.
+func (o *OrderedMap[K, V]) Remove(key K) {
+	hash := o.equality.Hash(key)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		// No entries with this hash code.
+		return
+	}
+	var zeroV V
+	for _, index := range indices {
+		if o.occupied[index] && o.equality.Equals(o.backing[index], key) {
+			// Found.  We mark the slot as a tombstone.
+			o.occupied[index] = false
+			o.valueBacking[index] = zeroV
+			o.len--
+			// If there was only one entry in the map with this hash code
+			// we might as well remove it.  TODO: We could also remove a
+			// single entry when there are hash collisions.
+			if len(indices) == 1 {
+				delete(o.hashToIndex, hash)
+			}
+		}
+	}
+}
+
+// If the set already contains an equal element, replace it with the given one.
+// If the map already contains an equal key, replace it with the given one.
+// The new element or key inherites the insertion order of the element or key
+// it replaces.
+func (o *orderedHash[T]) ReplaceWith(element T) {
+	hash := o.equality.Hash(element)
+	indices := o.hashToIndex[hash]
+	if indices == nil {
+		// Does not contain element/key.
+		return
+	}
+	for _, index := range indices {
+		if o.occupied[index] && o.equality.Equals(o.backing[index], element) {
+			// Already present in set/map.  Replace with given element/key.
+			o.backing[index] = element
+			return
+		}
+	}
+}
+
+// Iterable slice of the elements in the set or the keys in a map.
+// Iteration is in insertion order.  If the set or map is modified
+// during iteration the changes may or may not be reflected in this
+// slice.
+func (o *orderedHash[T]) Entries() []T {
+	if o.len == len(o.backing) {
+		return o.backing
+	}
+	result := make([]T, o.len)
+	i := 0
+	for index, entry := range o.backing {
+		if o.occupied[index] {
+			result[i] = entry
+			i = i + 1
+		}
+	}
+	return result
+}
+
+// Iterable slice of the values in a map.
+func (o *OrderedMap[K, V]) Values() []V {
+	if o.len == len(o.valueBacking) {
+		return o.valueBacking
+	}
+	result := make([]V, o.len)
+	i := 0
+	for index, entry := range o.valueBacking {
+		if o.occupied[index] {
+			result[i] = entry
+			i = i + 1
+		}
+	}
+	return result
+}
+
+// A reasonable equality function for strings.
+type StringEquality struct {
+}
+
+func (_ StringEquality) Equals(a string, b string) bool {
+	return a == b
+}
+
+func (_ StringEquality) Hash(a string) int {
+	h := 0
+	for _, char := range a {
+		h *= 11
+		h = h + int(char)
+	}
+	return h
+}
+
+func NewStringSet() *OrderedSet[string] {
+	return NewSet[string](StringEquality{})
+}
+
+func NewStringMap[V any]() *OrderedMap[string, V] {
+	return NewMap[string, V](StringEquality{})
+}
@@ -0,0 +1,272 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONKeyCodec lets a map key type round-trip through a JSON object key,
+// which the encoding/json package always represents as a string.  An
+// EqualityRelation that also implements JSONKeyCodec[K] can be used with
+// OrderedMap's MarshalJSON/UnmarshalJSON; StringEquality implements it so
+// that string-keyed maps work out of the box.  Keys that cannot be
+// sensibly stringified should use MarshalJSONAsPairs/UnmarshalJSONPairs
+// instead, which round-trip any JSON-marshalable key without a codec.
+type JSONKeyCodec[K any] interface {
+	EncodeJSONKey(key K) (string, error)
+	DecodeJSONKey(key string) (K, error)
+}
+
+func (_ StringEquality) EncodeJSONKey(key string) (string, error) {
+	return key, nil
+}
+
+func (_ StringEquality) DecodeJSONKey(key string) (string, error) {
+	return key, nil
+}
+
+// MarshalJSON marshals the set as a JSON array, in insertion order.
+func (o *OrderedSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Entries())
+}
+
+// UnmarshalJSON replaces the set's contents with the elements of a JSON
+// array, added in the order they appear.  Any elements already in the set
+// are discarded first.  The set must already have been constructed (e.g.
+// via NewSet) so that it has an EqualityRelation.
+func (o *OrderedSet[T]) UnmarshalJSON(data []byte) error {
+	o.clear()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var element T
+		if err := dec.Decode(&element); err != nil {
+			return err
+		}
+		o.Add(element)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// MarshalJSON marshals the map as a JSON object, with keys in insertion
+// order, e.g. {"k1":v1,"k2":v2,...}.  This requires the map's
+// EqualityRelation to implement JSONKeyCodec[K]; maps with keys that
+// cannot be stringified should use MarshalJSONAsPairs instead.
+func (o *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	codec, ok := any(o.equality).(JSONKeyCodec[K])
+	if !ok {
+		return nil, fmt.Errorf("orderedhash: %T has no JSONKeyCodec; use MarshalJSONAsPairs instead", o.equality)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	keys := o.Entries()
+	values := o.Values()
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyString, err := codec.EncodeJSONKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keyJSON, err := json.Marshal(keyString)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(valueJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the map's contents with the key-value pairs of a
+// JSON object, set in the order the keys appear.  Any pairs already in the
+// map are discarded first.  This requires the map's EqualityRelation to
+// implement JSONKeyCodec[K]; see MarshalJSON.
+func (o *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	codec, ok := any(o.equality).(JSONKeyCodec[K])
+	if !ok {
+		return fmt.Errorf("orderedhash: %T has no JSONKeyCodec; use UnmarshalJSONPairs instead", o.equality)
+	}
+	o.clear()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		keyToken, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		keyString, ok := keyToken.(string)
+		if !ok {
+			return fmt.Errorf("orderedhash: expected a JSON string key, got %v", keyToken)
+		}
+		key, err := codec.DecodeJSONKey(keyString)
+		if err != nil {
+			return err
+		}
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		o.Set(key, value)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// MarshalJSONAsPairs marshals the map as a JSON array of [key, value]
+// pairs, in insertion order, e.g. [["k1",v1],["k2",v2],...].  Unlike
+// MarshalJSON this does not require a JSONKeyCodec: any key type that
+// encoding/json can marshal works, including non-string keys.
+func (o *OrderedMap[K, V]) MarshalJSONAsPairs() ([]byte, error) {
+	keys := o.Entries()
+	values := o.Values()
+	pairs := make([][2]json.RawMessage, len(keys))
+	for i, key := range keys {
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		pairs[i] = [2]json.RawMessage{keyJSON, valueJSON}
+	}
+	return json.Marshal(pairs)
+}
+
+// UnmarshalJSONPairs replaces the map's contents with the [key, value]
+// pairs produced by MarshalJSONAsPairs, set in the order they appear.  Any
+// pairs already in the map are discarded first.
+func (o *OrderedMap[K, V]) UnmarshalJSONPairs(data []byte) error {
+	var pairs [][2]json.RawMessage
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	o.clear()
+	for _, pair := range pairs {
+		var key K
+		if err := json.Unmarshal(pair[0], &key); err != nil {
+			return err
+		}
+		var value V
+		if err := json.Unmarshal(pair[1], &value); err != nil {
+			return err
+		}
+		o.Set(key, value)
+	}
+	return nil
+}
+
+// clear empties the set/map in place, discarding every element/key-value
+// pair, so that Unmarshal* can give the set/map the "replace" semantics
+// their doc comments promise rather than merging into whatever was there
+// before.
+func (o *orderedHash[T]) clear() {
+	o.hashToIndex = make(map[int][]int)
+	o.backing = []T{}
+	o.occupied = []bool{}
+	o.len = 0
+}
+
+func (o *OrderedMap[K, V]) clear() {
+	o.orderedHash.clear()
+	o.valueBacking = []V{}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok || delim != want {
+		return fmt.Errorf("orderedhash: expected %q, got %v", want, token)
+	}
+	return nil
+}
+
+// gobPair is the on-the-wire shape used by OrderedMap's gob encoding; its
+// fields must be exported for encoding/gob to see them.
+type gobPair[K any, V any] struct {
+	Key   K
+	Value V
+}
+
+// GobEncode encodes the set as a gob-encoded slice of its elements, in
+// insertion order.
+func (o *OrderedSet[T]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(o.Entries()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the set's contents with the elements of a gob-encoded
+// slice produced by GobEncode, added in the order they were encoded.  Any
+// elements already in the set are discarded first.  The set must already
+// have been constructed (e.g. via NewSet) so that it has an
+// EqualityRelation.
+func (o *OrderedSet[T]) GobDecode(data []byte) error {
+	var entries []T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+	o.clear()
+	for _, element := range entries {
+		o.Add(element)
+	}
+	return nil
+}
+
+// GobEncode encodes the map as a gob-encoded slice of key-value pairs, in
+// insertion order.
+func (o *OrderedMap[K, V]) GobEncode() ([]byte, error) {
+	keys := o.Entries()
+	values := o.Values()
+	pairs := make([]gobPair[K, V], len(keys))
+	for i, key := range keys {
+		pairs[i] = gobPair[K, V]{Key: key, Value: values[i]}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pairs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the map's contents with the key-value pairs of a
+// gob-encoded slice produced by GobEncode, set in the order they were
+// encoded.  Any pairs already in the map are discarded first.  The map
+// must already have been constructed (e.g. via NewMap) so that it has an
+// EqualityRelation.
+func (o *OrderedMap[K, V]) GobDecode(data []byte) error {
+	var pairs []gobPair[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&pairs); err != nil {
+		return err
+	}
+	o.clear()
+	for _, pair := range pairs {
+		o.Set(pair.Key, pair.Value)
+	}
+	return nil
+}
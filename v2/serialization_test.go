@@ -0,0 +1,184 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetJSON(t *testing.T) {
+	s := stringSetOf("Foo", "Bar", "Fizz")
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["Foo","Bar","Fizz"]` {
+		t.Fatal(string(data))
+	}
+
+	decoded := NewStringSet()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Entries(); len(got) != 3 || got[0] != "Foo" || got[1] != "Bar" || got[2] != "Fizz" {
+		t.Fatal(got)
+	}
+}
+
+func TestMapJSON(t *testing.T) {
+	m := NewStringMap[int]()
+	m.Set("Foo", 1)
+	m.Set("Bar", 2)
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Foo":1,"Bar":2}` {
+		t.Fatal(string(data))
+	}
+
+	decoded := NewStringMap[int]()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Entries(); len(got) != 2 || got[0] != "Foo" || got[1] != "Bar" {
+		t.Fatal(got)
+	}
+	if v, _ := decoded.Get("Foo"); v != 1 {
+		t.Fatal(v)
+	}
+}
+
+func TestUnmarshalJSONReplacesExistingContents(t *testing.T) {
+	s := stringSetOf("Stale")
+	if err := json.Unmarshal([]byte(`["Foo","Bar"]`), s); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Entries(); len(got) != 2 || got[0] != "Foo" || got[1] != "Bar" {
+		t.Fatal(got)
+	}
+
+	m := NewStringMap[int]()
+	m.Set("stale", 99)
+	if err := json.Unmarshal([]byte(`{"a":1}`), m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Entries(); len(got) != 1 || got[0] != "a" {
+		t.Fatal(got)
+	}
+	if m.Contains("stale") {
+		t.Fatal("stale entry survived UnmarshalJSON")
+	}
+
+	pairsMap := NewMap[int, string](ComparableEquality[int]{HashFunc: func(i int) int { return i }})
+	pairsMap.Set(99, "stale")
+	freshMap := NewMap[int, string](ComparableEquality[int]{HashFunc: func(i int) int { return i }})
+	freshMap.Set(1, "one")
+	data, err := freshMap.MarshalJSONAsPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pairsMap.UnmarshalJSONPairs(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := pairsMap.Entries(); len(got) != 1 || got[0] != 1 {
+		t.Fatal(got)
+	}
+	if pairsMap.Contains(99) {
+		t.Fatal("stale entry survived UnmarshalJSONPairs")
+	}
+}
+
+func TestMapJSONAsPairs(t *testing.T) {
+	m := NewMap[int, string](ComparableEquality[int]{HashFunc: func(i int) int { return i }})
+	m.Set(2, "two")
+	m.Set(1, "one")
+	data, err := m.MarshalJSONAsPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[[2,"two"],[1,"one"]]` {
+		t.Fatal(string(data))
+	}
+
+	decoded := NewMap[int, string](ComparableEquality[int]{HashFunc: func(i int) int { return i }})
+	if err := decoded.UnmarshalJSONPairs(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Entries(); len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Fatal(got)
+	}
+}
+
+func TestSetGob(t *testing.T) {
+	s := stringSetOf("Foo", "Bar", "Fizz")
+	data, err := s.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewStringSet()
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Entries(); len(got) != 3 || got[0] != "Foo" || got[1] != "Bar" || got[2] != "Fizz" {
+		t.Fatal(got)
+	}
+}
+
+func TestMapGob(t *testing.T) {
+	m := NewStringMap[int]()
+	m.Set("Foo", 1)
+	m.Set("Bar", 2)
+	data, err := m.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewStringMap[int]()
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Entries(); len(got) != 2 || got[0] != "Foo" || got[1] != "Bar" {
+		t.Fatal(got)
+	}
+	if v, _ := decoded.Get("Bar"); v != 2 {
+		t.Fatal(v)
+	}
+}
+
+func TestGobDecodeReplacesExistingContents(t *testing.T) {
+	data, err := stringSetOf("Foo", "Bar").GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := stringSetOf("Stale")
+	if err := s.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Entries(); len(got) != 2 || got[0] != "Foo" || got[1] != "Bar" {
+		t.Fatal(got)
+	}
+	if s.Contains("Stale") {
+		t.Fatal("stale entry survived GobDecode")
+	}
+
+	fresh := NewStringMap[int]()
+	fresh.Set("a", 1)
+	mapData, err := fresh.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewStringMap[int]()
+	m.Set("stale", 99)
+	if err := m.GobDecode(mapData); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Entries(); len(got) != 1 || got[0] != "a" {
+		t.Fatal(got)
+	}
+	if m.Contains("stale") {
+		t.Fatal("stale entry survived GobDecode")
+	}
+}
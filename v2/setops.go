@@ -0,0 +1,135 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+// Set-algebra and map-merge operations.  New sets and maps are built with
+// the receiver's EqualityRelation, and membership in the receiver is checked
+// with it; membership in other (in Intersection, IntersectWith, Difference
+// and SymmetricDifference) is checked with other.Contains, that is, with
+// other's EqualityRelation.  The two operands must therefore use compatible
+// equality relations (in particular, equal elements must hash the same way
+// under both).  No attempt is made to reconcile two sets or maps that
+// disagree about equality.
+
+// Union returns a new set containing the receiver's elements, in the
+// receiver's order, followed by the elements of other that are not already
+// present, in other's order.
+func (o *OrderedSet[T]) Union(other *OrderedSet[T]) *OrderedSet[T] {
+	result := NewSet[T](o.equality)
+	for _, element := range o.Entries() {
+		result.Add(element)
+	}
+	for _, element := range other.Entries() {
+		result.Add(element)
+	}
+	return result
+}
+
+// UnionWith adds every element of other to the receiver, in other's order.
+// This is the in-place counterpart of Union.
+func (o *OrderedSet[T]) UnionWith(other *OrderedSet[T]) {
+	for _, element := range other.Entries() {
+		o.Add(element)
+	}
+}
+
+// Intersection returns a new set containing the elements the receiver and
+// other have in common, in the receiver's order.
+func (o *OrderedSet[T]) Intersection(other *OrderedSet[T]) *OrderedSet[T] {
+	result := NewSet[T](o.equality)
+	for _, element := range o.Entries() {
+		if other.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// IntersectWith removes every element from the receiver that is not also in
+// other.  This is the in-place counterpart of Intersection.
+func (o *OrderedSet[T]) IntersectWith(other *OrderedSet[T]) {
+	for _, element := range o.Entries() {
+		if !other.Contains(element) {
+			o.Remove(element)
+		}
+	}
+}
+
+// Difference returns a new set containing the receiver's elements, in the
+// receiver's order, that are not present in other.
+func (o *OrderedSet[T]) Difference(other *OrderedSet[T]) *OrderedSet[T] {
+	result := NewSet[T](o.equality)
+	for _, element := range o.Entries() {
+		if !other.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// SubtractFrom removes every element of other from the receiver.  This is
+// the in-place counterpart of Difference.
+func (o *OrderedSet[T]) SubtractFrom(other *OrderedSet[T]) {
+	for _, element := range other.Entries() {
+		o.Remove(element)
+	}
+}
+
+// SymmetricDifference returns a new set containing the elements that are in
+// exactly one of the receiver and other: first the receiver's elements that
+// are absent from other, in the receiver's order, then other's elements
+// that are absent from the receiver, in other's order.
+func (o *OrderedSet[T]) SymmetricDifference(other *OrderedSet[T]) *OrderedSet[T] {
+	result := o.Difference(other)
+	for _, element := range other.Entries() {
+		if !o.Contains(element) {
+			result.Add(element)
+		}
+	}
+	return result
+}
+
+// MergeMap returns a new map containing the receiver's key-value pairs, in
+// the receiver's order, overlaid with other's pairs, in other's order.  When
+// a key is present in both maps, resolve is called with the receiver's
+// value as oldV and other's value as newV, and its result becomes the
+// value in the merged map; the key keeps the receiver's insertion order.
+// Passing a resolve that always returns newV gives "last write wins";
+// always returning oldV gives "keep first".
+func (o *OrderedMap[K, V]) MergeMap(other *OrderedMap[K, V], resolve func(oldV, newV V) V) *OrderedMap[K, V] {
+	result := NewMap[K, V](o.equality)
+	keys := o.Entries()
+	values := o.Values()
+	for i, key := range keys {
+		result.Set(key, values[i])
+	}
+	otherKeys := other.Entries()
+	otherValues := other.Values()
+	for i, key := range otherKeys {
+		if oldV, ok := result.Get(key); ok {
+			result.Set(key, resolve(oldV, otherValues[i]))
+		} else {
+			result.Set(key, otherValues[i])
+		}
+	}
+	return result
+}
+
+// OverlayMap merges other's key-value pairs into the receiver in place, in
+// other's order.  When a key is present in both maps, resolve is called
+// with the receiver's value as oldV and other's value as newV, and its
+// result replaces the value in the receiver.  This is the in-place
+// counterpart of MergeMap.
+func (o *OrderedMap[K, V]) OverlayMap(other *OrderedMap[K, V], resolve func(oldV, newV V) V) {
+	keys := other.Entries()
+	values := other.Values()
+	for i, key := range keys {
+		if oldV, ok := o.Get(key); ok {
+			o.Set(key, resolve(oldV, values[i]))
+		} else {
+			o.Set(key, values[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import (
+	"reflect"
+	"testing"
+)
+
+func stringSetOf(elements ...string) *OrderedSet[string] {
+	s := NewStringSet()
+	for _, element := range elements {
+		s.Add(element)
+	}
+	return s
+}
+
+func TestSetOps(t *testing.T) {
+	a := stringSetOf("Foo", "Bar", "Fizz")
+	b := stringSetOf("Fizz", "Buzz", "Bar")
+
+	if got := a.Union(b).Entries(); !reflect.DeepEqual(got, []string{"Foo", "Bar", "Fizz", "Buzz"}) {
+		t.Fatal(got)
+	}
+	if got := a.Intersection(b).Entries(); !reflect.DeepEqual(got, []string{"Bar", "Fizz"}) {
+		t.Fatal(got)
+	}
+	if got := a.Difference(b).Entries(); !reflect.DeepEqual(got, []string{"Foo"}) {
+		t.Fatal(got)
+	}
+	if got := a.SymmetricDifference(b).Entries(); !reflect.DeepEqual(got, []string{"Foo", "Buzz"}) {
+		t.Fatal(got)
+	}
+
+	union := stringSetOf("Foo", "Bar", "Fizz")
+	union.UnionWith(b)
+	if got := union.Entries(); !reflect.DeepEqual(got, []string{"Foo", "Bar", "Fizz", "Buzz"}) {
+		t.Fatal(got)
+	}
+
+	intersect := stringSetOf("Foo", "Bar", "Fizz")
+	intersect.IntersectWith(b)
+	if got := intersect.Entries(); !reflect.DeepEqual(got, []string{"Bar", "Fizz"}) {
+		t.Fatal(got)
+	}
+
+	subtract := stringSetOf("Foo", "Bar", "Fizz")
+	subtract.SubtractFrom(b)
+	if got := subtract.Entries(); !reflect.DeepEqual(got, []string{"Foo"}) {
+		t.Fatal(got)
+	}
+}
+
+func TestMergeMap(t *testing.T) {
+	a := NewStringMap[int]()
+	a.Set("Foo", 1)
+	a.Set("Bar", 2)
+	b := NewStringMap[int]()
+	b.Set("Bar", 20)
+	b.Set("Fizz", 3)
+
+	lastWriteWins := func(oldV, newV int) int { return newV }
+	merged := a.MergeMap(b, lastWriteWins)
+	if got := merged.Entries(); !reflect.DeepEqual(got, []string{"Foo", "Bar", "Fizz"}) {
+		t.Fatal(got)
+	}
+	if got := merged.Values(); !reflect.DeepEqual(got, []int{1, 20, 3}) {
+		t.Fatal(got)
+	}
+
+	keepFirst := func(oldV, newV int) int { return oldV }
+	overlay := NewStringMap[int]()
+	overlay.Set("Foo", 1)
+	overlay.Set("Bar", 2)
+	overlay.OverlayMap(b, keepFirst)
+	if got := overlay.Entries(); !reflect.DeepEqual(got, []string{"Foo", "Bar", "Fizz"}) {
+		t.Fatal(got)
+	}
+	if got := overlay.Values(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatal(got)
+	}
+}
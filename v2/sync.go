@@ -0,0 +1,237 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import "sync"
+
+// SyncOrderedSet wraps an OrderedSet with a sync.RWMutex, making it safe
+// for concurrent use.  Unlike the plain OrderedSet, Entries() returns a
+// copy rather than aliasing the internal backing slice, since that slice
+// may be reallocated by a concurrent writer.
+type SyncOrderedSet[T any] struct {
+	mu  sync.RWMutex
+	set *OrderedSet[T]
+}
+
+// Create an empty, thread-safe insertion-ordered set with customized
+// equality function.
+func NewSyncSet[T any](relation EqualityRelation[T]) *SyncOrderedSet[T] {
+	return &SyncOrderedSet[T]{set: NewSet[T](relation)}
+}
+
+func NewSyncStringSet() *SyncOrderedSet[string] {
+	return NewSyncSet[string](StringEquality{})
+}
+
+// Add the element to the set if it does not already contain an equal element.
+func (o *SyncOrderedSet[T]) Add(element T) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.set.Add(element)
+}
+
+// Remove an equal element from the set.
+func (o *SyncOrderedSet[T]) Remove(element T) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.set.Remove(element)
+}
+
+// If the set already contains an equal element, replace it with the given
+// one.
+func (o *SyncOrderedSet[T]) ReplaceWith(element T) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.set.ReplaceWith(element)
+}
+
+// Compact immediately reclaims tombstone slots left behind by Remove.
+func (o *SyncOrderedSet[T]) Compact() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.set.Compact()
+}
+
+// Whether the set contains an equal element.
+func (o *SyncOrderedSet[T]) Contains(element T) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.set.Contains(element)
+}
+
+// Get an equal element that is already in the set.
+func (o *SyncOrderedSet[T]) GetKey(element T) (T, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.set.GetKey(element)
+}
+
+// Get the number of elements in the set.
+func (o *SyncOrderedSet[T]) Len() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.set.Len()
+}
+
+// Entries returns a copy of the elements in the set, in insertion order.
+// Unlike OrderedSet.Entries, this never aliases the internal backing
+// slice, so it is safe to keep and use after releasing the lock.
+func (o *SyncOrderedSet[T]) Entries() []T {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	src := o.set.Entries()
+	result := make([]T, len(src))
+	copy(result, src)
+	return result
+}
+
+// Snapshot returns an independent, unsynchronized OrderedSet with the same
+// contents as the receiver at the time of the call.  It is suitable for
+// long iteration without blocking concurrent writers, since it shares no
+// memory with the SyncOrderedSet.
+func (o *SyncOrderedSet[T]) Snapshot() *OrderedSet[T] {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.set.clone()
+}
+
+func (o *OrderedSet[T]) clone() *OrderedSet[T] {
+	return &OrderedSet[T]{orderedHash: o.orderedHash.clone()}
+}
+
+func (o *orderedHash[T]) clone() orderedHash[T] {
+	newBacking := make([]T, len(o.backing))
+	copy(newBacking, o.backing)
+	newOccupied := make([]bool, len(o.occupied))
+	copy(newOccupied, o.occupied)
+	newHashToIndex := make(map[int][]int, len(o.hashToIndex))
+	for hash, indices := range o.hashToIndex {
+		copiedIndices := make([]int, len(indices))
+		copy(copiedIndices, indices)
+		newHashToIndex[hash] = copiedIndices
+	}
+	return orderedHash[T]{
+		hashToIndex: newHashToIndex,
+		backing:     newBacking,
+		occupied:    newOccupied,
+		equality:    o.equality,
+		len:         o.len,
+	}
+}
+
+// SyncOrderedMap wraps an OrderedMap with a sync.RWMutex, making it safe
+// for concurrent use.  Unlike the plain OrderedMap, Entries() and Values()
+// return copies rather than aliasing internal backing slices, since those
+// slices may be reallocated by a concurrent writer.
+type SyncOrderedMap[K any, V any] struct {
+	mu sync.RWMutex
+	m  *OrderedMap[K, V]
+}
+
+// Create an empty, thread-safe insertion-ordered map with customized
+// equality function.
+func NewSyncMap[K any, V any](relation EqualityRelation[K]) *SyncOrderedMap[K, V] {
+	return &SyncOrderedMap[K, V]{m: NewMap[K, V](relation)}
+}
+
+func NewSyncStringMap[V any]() *SyncOrderedMap[string, V] {
+	return NewSyncMap[string, V](StringEquality{})
+}
+
+// Add the key and value to the map.  If the map already contains an equal
+// key then the value is overwritten, but the key is unchanged.
+func (o *SyncOrderedMap[K, V]) Set(key K, value V) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.m.Set(key, value)
+}
+
+// Remove an equal key and its associated value from the map.
+func (o *SyncOrderedMap[K, V]) Remove(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.m.Remove(key)
+}
+
+// If the map already contains an equal key, replace it with the given one.
+func (o *SyncOrderedMap[K, V]) ReplaceWith(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.m.ReplaceWith(key)
+}
+
+// Compact immediately reclaims tombstone slots left behind by Remove.
+func (o *SyncOrderedMap[K, V]) Compact() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.m.Compact()
+}
+
+// Whether the map contains an equal key.
+func (o *SyncOrderedMap[K, V]) Contains(key K) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.m.Contains(key)
+}
+
+// Get an equal key that is already in the map.
+func (o *SyncOrderedMap[K, V]) GetKey(key K) (K, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.m.GetKey(key)
+}
+
+// Get the value corresponding to a key.
+func (o *SyncOrderedMap[K, V]) Get(key K) (V, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.m.Get(key)
+}
+
+// Get the number of key-value pairs in the map.
+func (o *SyncOrderedMap[K, V]) Len() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.m.Len()
+}
+
+// Entries returns a copy of the keys in the map, in insertion order.
+// Unlike OrderedMap.Entries, this never aliases the internal backing
+// slice, so it is safe to keep and use after releasing the lock.
+func (o *SyncOrderedMap[K, V]) Entries() []K {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	src := o.m.Entries()
+	result := make([]K, len(src))
+	copy(result, src)
+	return result
+}
+
+// Values returns a copy of the values in the map, in insertion order.
+// Unlike OrderedMap.Values, this never aliases the internal backing
+// slice, so it is safe to keep and use after releasing the lock.
+func (o *SyncOrderedMap[K, V]) Values() []V {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	src := o.m.Values()
+	result := make([]V, len(src))
+	copy(result, src)
+	return result
+}
+
+// Snapshot returns an independent, unsynchronized OrderedMap with the same
+// contents as the receiver at the time of the call.  It is suitable for
+// long iteration without blocking concurrent writers, since it shares no
+// memory with the SyncOrderedMap.
+func (o *SyncOrderedMap[K, V]) Snapshot() *OrderedMap[K, V] {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	newValueBacking := make([]V, len(o.m.valueBacking))
+	copy(newValueBacking, o.m.valueBacking)
+	return &OrderedMap[K, V]{
+		orderedHash:  o.m.orderedHash.clone(),
+		valueBacking: newValueBacking,
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2021 Toitware ApS.  All rights reserved.
+// Use of this source code is governed by an MIT-style license that can be
+// found in the LICENSE file.
+
+package orderedhash
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSyncSet(t *testing.T) {
+	s := NewSyncStringSet()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Add(string(rune('a' + i%26)))
+		}(i)
+	}
+	wg.Wait()
+	if s.Len() != 26 {
+		t.Fatalf("Len() = %d, want 26", s.Len())
+	}
+
+	entries := s.Entries()
+	snapshot := s.Snapshot()
+	if !reflect.DeepEqual(entries, snapshot.Entries()) {
+		t.Fatal("snapshot does not match entries")
+	}
+	s.Add("extra")
+	if snapshot.Contains("extra") {
+		t.Fatal("snapshot observed a write made after it was taken")
+	}
+}
+
+func TestSyncMap(t *testing.T) {
+	m := NewSyncStringMap[int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Set(string(rune('a'+i%26)), i)
+		}(i)
+	}
+	wg.Wait()
+	if m.Len() != 26 {
+		t.Fatalf("Len() = %d, want 26", m.Len())
+	}
+
+	snapshot := m.Snapshot()
+	if !reflect.DeepEqual(m.Entries(), snapshot.Entries()) {
+		t.Fatal("snapshot keys do not match")
+	}
+	if !reflect.DeepEqual(m.Values(), snapshot.Values()) {
+		t.Fatal("snapshot values do not match")
+	}
+	m.Set("extra", -1)
+	if snapshot.Contains("extra") {
+		t.Fatal("snapshot observed a write made after it was taken")
+	}
+}